@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// WebhookConfig describes one outbound webhook fired after a successful
+// deploy.
+type WebhookConfig struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// DeployWebhookPayload is the JSON body posted to each configured
+// webhook after a deploy.
+type DeployWebhookPayload struct {
+	Event      string          `json:"event"`
+	Release    string          `json:"release"`
+	ExportType string          `json:"export_type"`
+	CommitID   string          `json:"commit_id"`
+	Files      []ManifestEntry `json:"files"`
+	DurationMS int64           `json:"duration_ms"`
+	Timestamp  time.Time       `json:"timestamp"`
+}
+
+// webhookHTTPClient is used for firing outbound webhooks, with a short
+// timeout so a slow or unreachable listener can't hang a deploy.
+var webhookHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// buildHugoSite runs before a release is finalized: if stagingDir looks
+// like a Hugo export (it has a content/ tree), it builds it with Hugo
+// into a fresh directory and, only once that build succeeds, swaps it
+// into place at Config.PublicPath (see publishHugoOutput). Callers must not call
+// finalizeRelease (which swaps the release's own current symlink) when
+// this returns an error — the old release and the old public output
+// are left untouched, so readers never see a half-written site.
+func buildHugoSite(stagingDir, exportType string) error {
+	if exportType != "hugo" {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(stagingDir, "content")); err != nil {
+		return nil
+	}
+	return runHugoBuild(stagingDir)
+}
+
+// notifyDeploy runs after a release has been finalized: it builds a
+// manifest of the finalized release and fires outbound webhooks.
+// Delivery is best-effort and never fails the deploy, which has already
+// succeeded by the time this runs.
+func notifyDeploy(basePath, exportType, releaseID string, start time.Time) {
+	releaseDir := filepath.Join(releasesDir(basePath), releaseID)
+
+	manifest, err := buildManifest(releaseDir)
+	if err != nil {
+		logger.Printf("Error building manifest for webhook payload: %v", err)
+	}
+
+	fireWebhooks(DeployWebhookPayload{
+		Event:      "deploy",
+		Release:    releaseID,
+		ExportType: exportType,
+		CommitID:   releaseID,
+		Files:      manifest,
+		DurationMS: time.Since(start).Milliseconds(),
+		Timestamp:  time.Now(),
+	})
+}
+
+// runHugoBuild shells out to the hugo binary to render srcDir into a
+// scratch build directory, and publishes it to Config.PublicPath only
+// if the build succeeds, so a build that fails
+// partway never leaves the published output half-written.
+func runHugoBuild(srcDir string) error {
+	buildDir, err := os.MkdirTemp(filepath.Dir(config.PublicPath), ".hugo-build-")
+	if err != nil {
+		return fmt.Errorf("creating hugo build directory: %w", err)
+	}
+	defer os.RemoveAll(buildDir)
+
+	cmd := exec.Command("hugo", "--source", srcDir, "--destination", buildDir, "--baseURL", config.BaseURL)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); err != nil {
+		if output.Len() > 0 {
+			logger.Printf("hugo build output for %s:\n%s", srcDir, output.String())
+		}
+		return err
+	}
+	if output.Len() > 0 {
+		logger.Printf("hugo build output for %s:\n%s", srcDir, output.String())
+	}
+
+	return publishHugoOutput(buildDir)
+}
+
+// publishHugoOutput swaps a freshly built output directory into place
+// at Config.PublicPath, moving whatever was previously published aside
+// first so the new output is only exposed once it's complete. The two
+// renames are separate steps: between them Config.PublicPath does not
+// exist.
+func publishHugoOutput(buildDir string) error {
+	previous := fmt.Sprintf("%s.previous-%d", config.PublicPath, time.Now().UnixNano())
+
+	if _, err := os.Stat(config.PublicPath); err == nil {
+		if err := os.Rename(config.PublicPath, previous); err != nil {
+			return fmt.Errorf("moving previous public output aside: %w", err)
+		}
+	}
+	if err := os.Rename(buildDir, config.PublicPath); err != nil {
+		os.Rename(previous, config.PublicPath)
+		return fmt.Errorf("publishing new public output: %w", err)
+	}
+
+	os.RemoveAll(previous)
+	return nil
+}
+
+// fireWebhooks posts payload to every configured webhook whose Events
+// list includes payload.Event (or is empty, meaning all events),
+// signing the body with HMAC-SHA256 over the webhook's secret.
+func fireWebhooks(payload DeployWebhookPayload) {
+	if len(config.Webhooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logger.Printf("Error marshaling webhook payload: %v", err)
+		return
+	}
+
+	for _, hook := range config.Webhooks {
+		if !webhookWantsEvent(hook, payload.Event) {
+			continue
+		}
+		go deliverWebhook(hook, body)
+	}
+}
+
+// webhookWantsEvent reports whether hook is subscribed to event. An
+// empty Events list subscribes to everything.
+func webhookWantsEvent(hook WebhookConfig, event string) bool {
+	if len(hook.Events) == 0 {
+		return true
+	}
+	for _, e := range hook.Events {
+		if strings.EqualFold(e, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverWebhook sends one signed webhook request, logging the outcome.
+// It never affects the deploy response: by the time this runs, the
+// deploy has already succeeded.
+func deliverWebhook(hook WebhookConfig, body []byte) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		logger.Printf("Error building webhook request for %s: %v", hook.URL, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Signature-256", "sha256="+signWebhookBody(hook.Secret, body))
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		logger.Printf("Error delivering webhook to %s: %v", hook.URL, err)
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		logger.Printf("Webhook to %s returned status %d", hook.URL, resp.StatusCode)
+		return
+	}
+	logger.Printf("Delivered webhook to %s", hook.URL)
+}
+
+// signWebhookBody returns the hex-encoded HMAC-SHA256 of body keyed by
+// secret, so receivers can verify the payload came from this server.
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -2,8 +2,6 @@
 package main
 
 import (
-	"archive/zip"
-	"bytes"
 	"crypto/subtle"
 	"encoding/json"
 	"fmt"
@@ -28,6 +26,16 @@ type Config struct {
 	BaseURL        string `json:"base_url"`
 	Title          string `json:"title"`
 	Theme          string `json:"theme"`
+	MaxReleases    int    `json:"max_releases"`
+
+	MaxUncompressedBytes int64 `json:"max_uncompressed_bytes"`
+	MaxEntries           int   `json:"max_entries"`
+	MaxEntryBytes        int64 `json:"max_entry_bytes"`
+
+	Storage StorageConfig `json:"storage"`
+
+	PublicPath string          `json:"public_path"`
+	Webhooks   []WebhookConfig `json:"webhooks"`
 }
 
 // Response is the standard API response structure.
@@ -65,10 +73,24 @@ func main() {
 		logger.Fatalf("Error creating deployment directory: %v", err)
 	}
 
+	// Set up the storage backend (local disk by default)
+	storageBackend, err := newStorage(config.Storage)
+	if err != nil {
+		logger.Fatalf("Error configuring storage backend: %v", err)
+	}
+	activeStorage = storageBackend
+
 	// Set up HTTP handlers
 	http.HandleFunc("/deploy", authenticateMiddleware(handleDeploy))
 	http.HandleFunc("/health", authenticateMiddleware(handleHealth))
 	http.HandleFunc("/info", authenticateMiddleware(handleInfo))
+	http.HandleFunc("/releases", authenticateMiddleware(handleReleases))
+	http.HandleFunc("/rollback", authenticateMiddleware(handleRollback))
+	http.HandleFunc("/deploy/tus", authenticateMiddleware(handleTusCreate))
+	http.HandleFunc("/deploy/tus/", authenticateMiddleware(handleTusUpload))
+	http.HandleFunc("/manifest", authenticateMiddleware(handleManifest))
+	http.HandleFunc("/deploy/delta", authenticateMiddleware(handleDeploymentDelta))
+	http.HandleFunc("/presign", authenticateMiddleware(handlePresign))
 
 	// Start server
 	logger.Printf("Deployment API server running on port %s...", config.Port)
@@ -153,6 +175,8 @@ func handleDeploy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	start := time.Now()
+
 	// 100MB file size limit
 	r.ParseMultipartForm(100 << 20)
 	
@@ -167,7 +191,15 @@ func handleDeploy(w http.ResponseWriter, r *http.Request) {
 	
 	// Get relative_path parameter (optional)
 	relativePath := r.FormValue("relative_path")
-	
+
+	// When a remote object-store backend is configured, uploaded files
+	// are written straight through the Storage interface instead of the
+	// local staged-release flow below, which is filesystem-specific.
+	if !isLocalStorageBackend() {
+		handleDeployViaStorage(w, r, exportType, relativePath)
+		return
+	}
+
 	// Determine deployment path based on export type and relative path
 	deployPath := filepath.Join(config.DeploymentPath, exportType)
 	if relativePath != "" {
@@ -186,6 +218,17 @@ func handleDeploy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Every deploy writes into a fresh release staging directory and is
+	// only made visible by swapping the current symlink once every file
+	// has been written and the release has been verified, so readers
+	// never see a half-written site.
+	staging, err := newReleaseStaging(deployPath)
+	if err != nil {
+		logger.Printf("Error staging release: %v", err)
+		sendResponse(w, false, fmt.Sprintf("Error staging release: %v", err), http.StatusInternalServerError)
+		return
+	}
+
 	// Check if this is a site initialization request with a ZIP file
 	isInit := r.FormValue("init") == "true"
 	if isInit {
@@ -195,58 +238,64 @@ func handleDeploy(w http.ResponseWriter, r *http.Request) {
 			logger.Printf("No template ZIP file provided: %v", err)
 		} else {
 			defer file.Close()
-			
+
 			logger.Printf("Received template ZIP file: %s (%d bytes)", header.Filename, header.Size)
-			
-			// Create a temporary file to store the ZIP
-			tempFile, err := ioutil.TempFile("", "template-*.zip")
-			if err != nil {
-				logger.Printf("Error creating temporary file: %v", err)
-				sendResponse(w, false, fmt.Sprintf("Error creating temporary file: %v", err), http.StatusInternalServerError)
+
+			// Extract straight from the multipart file (it implements
+			// io.ReaderAt) into the release staging directory, without
+			// copying it to a temp file of its own first. An upload larger
+			// than the ParseMultipartForm memory limit has still been
+			// spooled to disk by the multipart parser before this runs.
+			if err := extractZipReader(file, header.Size, staging); err != nil {
+				logger.Printf("Error extracting ZIP file: %v", err)
+				os.RemoveAll(staging)
+				sendResponse(w, false, fmt.Sprintf("Error extracting ZIP file: %v", err), http.StatusInternalServerError)
 				return
 			}
-			defer os.Remove(tempFile.Name())
-			defer tempFile.Close()
-			
-			// Copy the ZIP file to the temporary file
-			if _, err := io.Copy(tempFile, file); err != nil {
-				logger.Printf("Error copying ZIP file: %v", err)
-				sendResponse(w, false, fmt.Sprintf("Error copying ZIP file: %v", err), http.StatusInternalServerError)
+
+			// Build before finalizing: a failed Hugo build must never
+			// reach finalizeRelease, so current keeps pointing at the
+			// last good release.
+			if err := buildHugoSite(staging, exportType); err != nil {
+				logger.Printf("Error building site: %v", err)
+				os.RemoveAll(staging)
+				sendResponse(w, false, fmt.Sprintf("Error building site: %v", err), http.StatusInternalServerError)
 				return
 			}
-			
-			// Close the file to ensure all data is written
-			tempFile.Close()
-			
-			// Extract the ZIP file to the deployment path
-			if err := extractZip(tempFile.Name(), deployPath); err != nil {
-				logger.Printf("Error extracting ZIP file: %v", err)
-				sendResponse(w, false, fmt.Sprintf("Error extracting ZIP file: %v", err), http.StatusInternalServerError)
+
+			releaseID, err := finalizeRelease(deployPath, staging)
+			if err != nil {
+				logger.Printf("Error finalizing release: %v", err)
+				os.RemoveAll(staging)
+				sendResponse(w, false, fmt.Sprintf("Error finalizing release: %v", err), http.StatusInternalServerError)
 				return
 			}
-			
-			logger.Printf("Extracted template ZIP file to %s", deployPath)
-			sendResponse(w, true, fmt.Sprintf("Successfully initialized %s site template at %s", exportType, deployPath), http.StatusOK)
+
+			notifyDeploy(deployPath, exportType, releaseID, start)
+
+			logger.Printf("Extracted template ZIP file to release %s at %s", releaseID, deployPath)
+			sendResponse(w, true, fmt.Sprintf("Successfully initialized %s site template at release %s", exportType, releaseID), http.StatusOK)
 			return
 		}
 	}
-	
+
 	// Process all files
 	form := r.MultipartForm
 	files := form.File["files"]
-	
+
 	if len(files) == 0 {
+		os.RemoveAll(staging)
 		sendResponse(w, false, "No files sent", http.StatusBadRequest)
 		return
 	}
-	
+
 	var processedFiles []FileInfo
-	
+
 	for _, fileHeader := range files {
 		// Get filename and target path
 		filename := filepath.Base(fileHeader.Filename)
-		targetPath := filepath.Join(deployPath, filename)
-		
+		targetPath := filepath.Join(staging, filename)
+
 		// Open source file
 		src, err := fileHeader.Open()
 		if err != nil {
@@ -254,7 +303,7 @@ func handleDeploy(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		defer src.Close()
-		
+
 		// Create target file
 		dst, err := os.Create(targetPath)
 		if err != nil {
@@ -262,38 +311,64 @@ func handleDeploy(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 		defer dst.Close()
-		
+
 		// Copy file contents
 		if _, err = io.Copy(dst, src); err != nil {
 			logger.Printf("Error copying file %s: %v", filename, err)
 			continue
 		}
-		
+
 		// Add to processed files list
 		processedFiles = append(processedFiles, FileInfo{
 			Path:        filepath.Join(relativePath, filename),
 			ContentType: fileHeader.Header.Get("Content-Type"),
 			Size:        fileHeader.Size,
 		})
-		
+
 		logger.Printf("File saved successfully: %s (%d bytes)", targetPath, fileHeader.Size)
 	}
-	
+
+	// A file that could not be written fails the whole deploy rather than
+	// being left out of a release that is then made current.
+	if len(processedFiles) != len(files) {
+		os.RemoveAll(staging)
+		sendResponse(w, false, fmt.Sprintf("Error saving files: %d of %d saved", len(processedFiles), len(files)), http.StatusInternalServerError)
+		return
+	}
+
+	// Build before finalizing: a failed Hugo build must never reach
+	// finalizeRelease, so current keeps pointing at the last good release.
+	if err := buildHugoSite(staging, exportType); err != nil {
+		logger.Printf("Error building site: %v", err)
+		os.RemoveAll(staging)
+		sendResponse(w, false, fmt.Sprintf("Error building site: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	releaseID, err := finalizeRelease(deployPath, staging)
+	if err != nil {
+		logger.Printf("Error finalizing release: %v", err)
+		os.RemoveAll(staging)
+		sendResponse(w, false, fmt.Sprintf("Error finalizing release: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	notifyDeploy(deployPath, exportType, releaseID, start)
+
 	// Send response with processed files list
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	
 	response := struct {
 		Success bool       `json:"success"`
 		Message string     `json:"message"`
+		Release string     `json:"release"`
 		Files   []FileInfo `json:"files"`
 	}{
 		Success: true,
-		Message: fmt.Sprintf("Successfully saved %d files to %s", len(processedFiles), deployPath),
+		Message: fmt.Sprintf("Successfully saved %d files to release %s", len(processedFiles), releaseID),
+		Release: releaseID,
 		Files:   processedFiles,
 	}
-	
-	json.NewEncoder(w).Encode(response)
+
+	writeJSON(w, http.StatusOK, response)
 }
 
 // sendResponse sends a standard JSON response.
@@ -341,6 +416,13 @@ func copyDirectory(src, dst string) error {
 			if err := copyDirectory(srcPath, dstPath); err != nil {
 				return err
 			}
+		} else if entry.Mode()&os.ModeSymlink != 0 {
+			// Recreate symlinks instead of copying the target they
+			// point to, so a delta-synced release keeps the same
+			// symlink entries a ZIP-extracted one would have.
+			if err := copySymlink(srcPath, dstPath); err != nil {
+				return err
+			}
 		} else {
 			// Copy file
 			if err := copyFile(srcPath, dstPath); err != nil {
@@ -382,67 +464,12 @@ func copyFile(src, dst string) error {
 	return os.Chmod(dst, srcInfo.Mode())
 }
 
-// extractZip extracts a ZIP file to the specified destination directory.
-func extractZip(zipFile, destDir string) error {
-	// Open the ZIP file
-	reader, err := zip.OpenReader(zipFile)
+// copySymlink recreates src, a symlink, at dst pointing at the same
+// target rather than copying the contents of whatever it resolves to.
+func copySymlink(src, dst string) error {
+	target, err := os.Readlink(src)
 	if err != nil {
 		return err
 	}
-	defer reader.Close()
-	
-	// Create destination directory if it doesn't exist
-	if err := os.MkdirAll(destDir, 0755); err != nil {
-		return err
-	}
-	
-	// Extract each file
-	for _, file := range reader.File {
-		// Construct the full path for the extracted file
-		path := filepath.Join(destDir, file.Name)
-		
-		// Check for directory traversal attacks
-		if !strings.HasPrefix(path, filepath.Clean(destDir)+string(os.PathSeparator)) {
-			return fmt.Errorf("illegal file path: %s", file.Name)
-		}
-		
-		// If it's a directory, create it
-		if file.FileInfo().IsDir() {
-			if err := os.MkdirAll(path, file.Mode()); err != nil {
-				return err
-			}
-			continue
-		}
-		
-		// Create the directory for the file if it doesn't exist
-		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-			return err
-		}
-		
-		// Open the file from the ZIP
-		fileReader, err := file.Open()
-		if err != nil {
-			return err
-		}
-		
-		// Create the file
-		fileWriter, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
-		if err != nil {
-			fileReader.Close()
-			return err
-		}
-		
-		// Copy the contents
-		if _, err := io.Copy(fileWriter, fileReader); err != nil {
-			fileReader.Close()
-			fileWriter.Close()
-			return err
-		}
-		
-		// Close both files
-		fileReader.Close()
-		fileWriter.Close()
-	}
-	
-	return nil
+	return os.Symlink(target, dst)
 }
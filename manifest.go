@@ -0,0 +1,318 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestEntry describes a single file's content address within a
+// deploy path, as returned by /manifest and submitted to /deploy/delta.
+type ManifestEntry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// isSafeRelPath reports whether a client-supplied relative path is safe
+// to join under a deploy directory: no "..", and not absolute.
+func isSafeRelPath(path string) bool {
+	if path == "" || filepath.IsAbs(path) {
+		return false
+	}
+	clean := filepath.ToSlash(filepath.Clean(path))
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return false
+	}
+	return true
+}
+
+// currentReleasePath resolves the directory a deploy path's current
+// symlink points at, or "" if no release has been deployed yet.
+func currentReleasePath(basePath string) (string, error) {
+	target, err := os.Readlink(currentLink(basePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(basePath, target)
+	}
+	return target, nil
+}
+
+// buildManifest walks a release directory and returns the sha256 and
+// size of every file it contains, relative to that directory.
+func buildManifest(dir string) ([]ManifestEntry, error) {
+	var entries []ManifestEntry
+
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return entries, nil
+	}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, ManifestEntry{
+			Path:   filepath.ToSlash(rel),
+			Size:   info.Size(),
+			SHA256: hex.EncodeToString(h.Sum(nil)),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, nil
+}
+
+// handleManifest returns the content-addressed manifest of every file
+// under a deploy path's current release, so a client can diff it
+// against its own build and push only what changed.
+func handleManifest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendResponse(w, false, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isLocalStorageBackend() {
+		sendResponse(w, false, "Manifest lookup is not supported with the configured storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	basePath, err := resolveDeployBase(r)
+	if err != nil {
+		sendResponse(w, false, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	releasePath, err := currentReleasePath(basePath)
+	if err != nil {
+		logger.Printf("Error resolving current release for %s: %v", basePath, err)
+		sendResponse(w, false, fmt.Sprintf("Error resolving current release: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	manifest, err := buildManifest(releasePath)
+	if err != nil {
+		logger.Printf("Error building manifest for %s: %v", releasePath, err)
+		sendResponse(w, false, fmt.Sprintf("Error building manifest: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Success bool            `json:"success"`
+		Files   []ManifestEntry `json:"files"`
+	}{true, manifest})
+}
+
+// handleDeploymentDelta accepts a partial release: a manifest of files
+// to add or update (each uploaded as a multipart part keyed by its own
+// sha256), plus an explicit list of paths to delete. It starts the new
+// release from a copy of the current one so unlisted files carry over
+// unchanged, then layers the delta on top.
+func handleDeploymentDelta(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendResponse(w, false, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isLocalStorageBackend() {
+		sendResponse(w, false, "Delta deploys are not supported with the configured storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	start := time.Now()
+	r.ParseMultipartForm(100 << 20)
+
+	basePath, err := resolveDeployBase(r)
+	if err != nil {
+		sendResponse(w, false, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	exportType := r.FormValue("export_type")
+	if exportType == "" {
+		exportType = config.ExportType
+		if exportType == "" {
+			exportType = "hugo"
+		}
+	}
+	if err := os.MkdirAll(basePath, 0755); err != nil {
+		logger.Printf("Error creating deployment directory: %v", err)
+		sendResponse(w, false, fmt.Sprintf("Error creating deployment directory: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var updates []ManifestEntry
+	if raw := r.FormValue("manifest"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &updates); err != nil {
+			sendResponse(w, false, fmt.Sprintf("Invalid manifest: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	var deletes []string
+	if raw := r.FormValue("delete"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &deletes); err != nil {
+			sendResponse(w, false, fmt.Sprintf("Invalid delete list: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, entry := range updates {
+		if !isSafeRelPath(entry.Path) {
+			sendResponse(w, false, fmt.Sprintf("Invalid path in manifest: %s", entry.Path), http.StatusBadRequest)
+			return
+		}
+	}
+	for _, path := range deletes {
+		if !isSafeRelPath(path) {
+			sendResponse(w, false, fmt.Sprintf("Invalid path in delete list: %s", path), http.StatusBadRequest)
+			return
+		}
+	}
+
+	releasePath, err := currentReleasePath(basePath)
+	if err != nil {
+		logger.Printf("Error resolving current release for %s: %v", basePath, err)
+		sendResponse(w, false, fmt.Sprintf("Error resolving current release: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	staging, err := newReleaseStaging(basePath)
+	if err != nil {
+		logger.Printf("Error staging release: %v", err)
+		sendResponse(w, false, fmt.Sprintf("Error staging release: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if releasePath != "" {
+		if err := copyDirectory(releasePath, staging); err != nil {
+			os.RemoveAll(staging)
+			logger.Printf("Error copying current release into staging: %v", err)
+			sendResponse(w, false, fmt.Sprintf("Error preparing delta: %v", err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for _, path := range deletes {
+		if err := os.RemoveAll(filepath.Join(staging, path)); err != nil {
+			os.RemoveAll(staging)
+			logger.Printf("Error deleting %s from staging: %v", path, err)
+			sendResponse(w, false, fmt.Sprintf("Error deleting %s: %v", path, err), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for _, entry := range updates {
+		if err := writeDeltaBlob(r, staging, entry); err != nil {
+			os.RemoveAll(staging)
+			sendResponse(w, false, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Build before finalizing: a failed Hugo build must never reach
+	// finalizeRelease, so current keeps pointing at the last good release.
+	if err := buildHugoSite(staging, exportType); err != nil {
+		os.RemoveAll(staging)
+		logger.Printf("Error building site: %v", err)
+		sendResponse(w, false, fmt.Sprintf("Error building site: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	releaseID, err := finalizeRelease(basePath, staging)
+	if err != nil {
+		os.RemoveAll(staging)
+		logger.Printf("Error finalizing delta release: %v", err)
+		sendResponse(w, false, fmt.Sprintf("Error finalizing release: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	notifyDeploy(basePath, exportType, releaseID, start)
+
+	logger.Printf("Applied delta release %s at %s (%d updated, %d deleted)", releaseID, basePath, len(updates), len(deletes))
+
+	writeJSON(w, http.StatusOK, struct {
+		Success bool   `json:"success"`
+		Release string `json:"release"`
+		Updated int    `json:"updated"`
+		Deleted int    `json:"deleted"`
+	}{true, releaseID, len(updates), len(deletes)})
+}
+
+// writeDeltaBlob copies one uploaded file part (keyed by its own sha256
+// hash in the multipart form) into staging at its manifest path,
+// rejecting the upload if the blob's hash or size don't match.
+func writeDeltaBlob(r *http.Request, staging string, entry ManifestEntry) error {
+	headers := r.MultipartForm.File[entry.SHA256]
+	if len(headers) == 0 {
+		return fmt.Errorf("missing uploaded blob for %s (%s)", entry.Path, entry.SHA256)
+	}
+
+	src, err := headers[0].Open()
+	if err != nil {
+		return fmt.Errorf("opening uploaded blob for %s: %w", entry.Path, err)
+	}
+	defer src.Close()
+
+	target := filepath.Join(staging, entry.Path)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("creating directory for %s: %w", entry.Path, err)
+	}
+
+	dst, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", entry.Path, err)
+	}
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(dst, h), src)
+	dst.Close()
+	if err != nil {
+		return fmt.Errorf("writing %s: %w", entry.Path, err)
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	if sum != entry.SHA256 {
+		return fmt.Errorf("sha256 mismatch for %s: expected %s, got %s", entry.Path, entry.SHA256, sum)
+	}
+	if size != entry.Size {
+		return fmt.Errorf("size mismatch for %s: expected %d, got %d", entry.Path, entry.Size, size)
+	}
+
+	return nil
+}
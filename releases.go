@@ -0,0 +1,346 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultMaxReleases is used when Config.MaxReleases is not set.
+const defaultMaxReleases = 10
+
+// ReleaseInfo describes a single release kept under a deploy path's
+// releases directory.
+type ReleaseInfo struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Current bool      `json:"current"`
+}
+
+// releasesDir returns the directory holding every staged and finalized
+// release for a given deploy base path.
+func releasesDir(basePath string) string {
+	return filepath.Join(basePath, "releases")
+}
+
+// currentLink returns the path of the symlink that always points at the
+// active release directory.
+func currentLink(basePath string) string {
+	return filepath.Join(basePath, "current")
+}
+
+// newReleaseStaging creates a fresh, uniquely named staging directory
+// under releases/ for a deploy to write its files into. The directory is
+// not visible to readers until finalizeRelease swaps the current symlink
+// to point at it.
+func newReleaseStaging(basePath string) (string, error) {
+	root := releasesDir(basePath)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", fmt.Errorf("creating releases directory: %w", err)
+	}
+
+	staging := filepath.Join(root, fmt.Sprintf(".staging-%d", time.Now().UnixNano()))
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return "", fmt.Errorf("creating release staging directory: %w", err)
+	}
+	return staging, nil
+}
+
+// hashReleaseDir walks a staged release directory and returns a sha256
+// digest over every file's relative path and contents, so that two
+// releases with identical contents get the same digest, which
+// finalizeRelease puts after the timestamp in the release ID.
+func hashReleaseDir(dir string) (string, error) {
+	h := sha256.New()
+
+	var paths []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, rel)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+
+	for _, rel := range paths {
+		fmt.Fprintf(h, "%s\x00", filepath.ToSlash(rel))
+		f, err := os.Open(filepath.Join(dir, rel))
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// finalizeRelease hashes a completed staging directory, renames it into
+// its content-addressed final location, and atomically swaps the
+// current symlink to point at it. It returns the new release ID.
+func finalizeRelease(basePath, staging string) (string, error) {
+	sum, err := hashReleaseDir(staging)
+	if err != nil {
+		return "", fmt.Errorf("hashing release: %w", err)
+	}
+
+	releaseID := fmt.Sprintf("%d-%s", time.Now().Unix(), sum[:12])
+	final := filepath.Join(releasesDir(basePath), releaseID)
+	if err := os.Rename(staging, final); err != nil {
+		return "", fmt.Errorf("moving release into place: %w", err)
+	}
+
+	if err := swapCurrent(basePath, releaseID); err != nil {
+		return "", err
+	}
+
+	pruneReleases(basePath)
+
+	return releaseID, nil
+}
+
+// swapCurrent atomically repoints the current symlink at the named
+// release. It builds the new link next to the old one and renames it
+// over the top, so readers never observe a missing symlink.
+func swapCurrent(basePath, releaseID string) error {
+	link := currentLink(basePath)
+	tmpLink := link + fmt.Sprintf(".tmp-%d", time.Now().UnixNano())
+
+	if err := os.Symlink(filepath.Join("releases", releaseID), tmpLink); err != nil {
+		return fmt.Errorf("creating symlink: %w", err)
+	}
+	if err := os.Rename(tmpLink, link); err != nil {
+		os.Remove(tmpLink)
+		return fmt.Errorf("swapping current symlink: %w", err)
+	}
+	return nil
+}
+
+// listReleases returns every finalized release under basePath, oldest
+// first, annotated with whether it is the one current points at.
+func listReleases(basePath string) ([]ReleaseInfo, error) {
+	entries, err := os.ReadDir(releasesDir(basePath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	active, _ := os.Readlink(currentLink(basePath))
+	active = filepath.Base(active)
+
+	var releases []ReleaseInfo
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".staging-") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		releases = append(releases, ReleaseInfo{
+			ID:      entry.Name(),
+			Created: info.ModTime(),
+			Current: entry.Name() == active,
+		})
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].ID < releases[j].ID
+	})
+	return releases, nil
+}
+
+// maxReleases returns the configured release retention, falling back to
+// defaultMaxReleases when unset.
+func maxReleases() int {
+	if config.MaxReleases > 0 {
+		return config.MaxReleases
+	}
+	return defaultMaxReleases
+}
+
+// pruneReleases removes the oldest finalized releases beyond the
+// configured retention, always keeping the one current points at.
+func pruneReleases(basePath string) {
+	releases, err := listReleases(basePath)
+	if err != nil {
+		logger.Printf("Error listing releases for pruning under %s: %v", basePath, err)
+		return
+	}
+
+	limit := maxReleases()
+	if len(releases) <= limit {
+		return
+	}
+
+	for _, release := range releases[:len(releases)-limit] {
+		if release.Current {
+			continue
+		}
+		path := filepath.Join(releasesDir(basePath), release.ID)
+		if err := os.RemoveAll(path); err != nil {
+			logger.Printf("Error pruning old release %s: %v", path, err)
+		}
+	}
+}
+
+// rollbackRelease repoints the current symlink at a previously
+// finalized release. releaseID must name a release listReleases already
+// knows about, so a caller can't use "../" or an absolute path to point
+// current outside basePath.
+func rollbackRelease(basePath, releaseID string) error {
+	releases, err := listReleases(basePath)
+	if err != nil {
+		return fmt.Errorf("listing releases: %w", err)
+	}
+	found := false
+	for _, release := range releases {
+		if release.ID == releaseID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("release %q not found", releaseID)
+	}
+	return swapCurrent(basePath, releaseID)
+}
+
+// resolveDeployBase validates export_type/relative_path form values the
+// same way handleDeploy does and returns the base deploy path they
+// identify.
+func resolveDeployBase(r *http.Request) (string, error) {
+	exportType := r.FormValue("export_type")
+	if exportType == "" {
+		exportType = config.ExportType
+		if exportType == "" {
+			exportType = "hugo"
+		}
+	}
+
+	relativePath := r.FormValue("relative_path")
+	basePath := filepath.Join(config.DeploymentPath, exportType)
+	if relativePath != "" {
+		if strings.Contains(relativePath, "..") {
+			return "", fmt.Errorf("invalid relative path")
+		}
+		basePath = filepath.Join(basePath, relativePath)
+	}
+	return basePath, nil
+}
+
+// handleReleases lists the releases available for a given deploy path.
+func handleReleases(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		sendResponse(w, false, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isLocalStorageBackend() {
+		sendResponse(w, false, "Release listing is not supported with the configured storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	basePath, err := resolveDeployBase(r)
+	if err != nil {
+		sendResponse(w, false, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	releases, err := listReleases(basePath)
+	if err != nil {
+		logger.Printf("Error listing releases under %s: %v", basePath, err)
+		sendResponse(w, false, fmt.Sprintf("Error listing releases: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	writeJSON(w, http.StatusOK, struct {
+		Success  bool          `json:"success"`
+		Releases []ReleaseInfo `json:"releases"`
+	}{true, releases})
+}
+
+// handleRollback repoints a deploy path's current symlink at a prior
+// release, atomically undoing the deploys since.
+func handleRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendResponse(w, false, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isLocalStorageBackend() {
+		sendResponse(w, false, "Rollback is not supported with the configured storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	r.ParseForm()
+
+	basePath, err := resolveDeployBase(r)
+	if err != nil {
+		sendResponse(w, false, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	releaseID := r.FormValue("release")
+	if releaseID == "" {
+		// No explicit target: roll back to the release before current.
+		releases, err := listReleases(basePath)
+		if err != nil || len(releases) < 2 {
+			sendResponse(w, false, "No prior release to roll back to", http.StatusBadRequest)
+			return
+		}
+		for i, release := range releases {
+			if release.Current && i > 0 {
+				releaseID = releases[i-1].ID
+				break
+			}
+		}
+		if releaseID == "" {
+			sendResponse(w, false, "No prior release to roll back to", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := rollbackRelease(basePath, releaseID); err != nil {
+		logger.Printf("Error rolling back %s to %s: %v", basePath, releaseID, err)
+		sendResponse(w, false, fmt.Sprintf("Error rolling back: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Printf("Rolled back %s to release %s", basePath, releaseID)
+	sendResponse(w, true, fmt.Sprintf("Rolled back to release %s", releaseID), http.StatusOK)
+}
+
+// writeJSON encodes v as the JSON response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Printf("Error encoding JSON response: %v", err)
+	}
+}
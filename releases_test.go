@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func stageAndFinalize(t *testing.T, basePath string, files map[string]string) string {
+	t.Helper()
+	staging, err := newReleaseStaging(basePath)
+	if err != nil {
+		t.Fatalf("staging release: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(staging, name), []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %v", name, err)
+		}
+	}
+	releaseID, err := finalizeRelease(basePath, staging)
+	if err != nil {
+		t.Fatalf("finalizing release: %v", err)
+	}
+	return releaseID
+}
+
+func TestRollbackRejectsUnknownRelease(t *testing.T) {
+	base := t.TempDir()
+	stageAndFinalize(t, base, map[string]string{"index.html": "v1"})
+
+	if err := rollbackRelease(base, "../../../../etc"); err == nil {
+		t.Fatal("expected an error for a path-traversal release id, got nil")
+	}
+	if target, err := os.Readlink(currentLink(base)); err != nil || filepath.Base(target) == "etc" {
+		t.Fatalf("current symlink was repointed outside releases/: target=%q err=%v", target, err)
+	}
+}
+
+func TestRollbackAcceptsKnownRelease(t *testing.T) {
+	base := t.TempDir()
+	first := stageAndFinalize(t, base, map[string]string{"index.html": "v1"})
+	stageAndFinalize(t, base, map[string]string{"index.html": "v2"})
+
+	if err := rollbackRelease(base, first); err != nil {
+		t.Fatalf("rolling back to a known release: %v", err)
+	}
+
+	target, err := os.Readlink(currentLink(base))
+	if err != nil {
+		t.Fatalf("reading current symlink: %v", err)
+	}
+	if filepath.Base(target) != first {
+		t.Fatalf("current points at %q, want %q", filepath.Base(target), first)
+	}
+}
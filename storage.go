@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// StorageObject describes a single object tracked by a Storage backend.
+type StorageObject struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	ModTime     time.Time `json:"mod_time"`
+}
+
+// Storage abstracts where deployed files actually live, so a site can be
+// published to local disk (the original behavior) or directly to an
+// object store that fronts a CDN.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]StorageObject, error)
+	Stat(ctx context.Context, key string) (StorageObject, error)
+}
+
+// Presigner is implemented by Storage backends that can hand out a
+// pre-signed PUT URL, letting very large assets be uploaded straight to
+// the object store without passing through this API.
+type Presigner interface {
+	PresignPut(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// StorageConfig selects and configures the active Storage backend.
+type StorageConfig struct {
+	Backend          string `json:"backend"` // "local" (default), "s3", "gcs", "azure"
+	Bucket           string `json:"bucket"`
+	Prefix           string `json:"prefix"`
+	Region           string `json:"region"`
+	Endpoint         string `json:"endpoint"`
+	AccessKey        string `json:"access_key"`
+	SecretKey        string `json:"secret_key"`
+	ConnectionString string `json:"connection_string"` // Azure
+}
+
+// activeStorage is the Storage backend selected by Config.Storage,
+// initialized once in main.
+var activeStorage Storage
+
+// isLocalStorageBackend reports whether activeStorage writes to the
+// local filesystem under DeploymentPath. The release/rollback/manifest/
+// delta machinery in releases.go and manifest.go reads and writes that
+// filesystem layout directly, so it only makes sense for this backend;
+// callers use this to reject those endpoints instead of silently
+// operating on stale or empty local state when a remote backend is
+// configured.
+func isLocalStorageBackend() bool {
+	_, ok := activeStorage.(*localStorage)
+	return ok
+}
+
+// newStorage builds the Storage backend selected by cfg. An empty or
+// "local" backend keeps writing to DeploymentPath on disk.
+func newStorage(cfg StorageConfig) (Storage, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return newLocalStorage(config.DeploymentPath), nil
+	case "s3":
+		return newS3Storage(cfg)
+	case "gcs":
+		return newGCSStorage(cfg)
+	case "azure":
+		return newAzureStorage(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage backend: %s", cfg.Backend)
+	}
+}
+
+// localStorage implements Storage over the local filesystem, rooted at
+// a base directory. This is the server's original behavior.
+type localStorage struct {
+	root string
+}
+
+func newLocalStorage(root string) *localStorage {
+	return &localStorage{root: root}
+}
+
+// resolve maps a storage key to a path under root, collapsing any ".."
+// segments so keys can't escape it.
+func (s *localStorage) resolve(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+func (s *localStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	target := s.resolve(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func (s *localStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return os.Open(s.resolve(key))
+}
+
+func (s *localStorage) Delete(ctx context.Context, key string) error {
+	return os.Remove(s.resolve(key))
+}
+
+func (s *localStorage) Stat(ctx context.Context, key string) (StorageObject, error) {
+	info, err := os.Stat(s.resolve(key))
+	if err != nil {
+		return StorageObject{}, err
+	}
+	return StorageObject{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+func (s *localStorage) List(ctx context.Context, prefix string) ([]StorageObject, error) {
+	root := s.resolve(prefix)
+	var objects []StorageObject
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, StorageObject{
+			Key:     filepath.ToSlash(rel),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// handleDeployViaStorage uploads the files of a /deploy request directly
+// through the active Storage backend. ZIP template initialization isn't
+// supported here yet: it relies on streaming straight into a local
+// staging directory, which doesn't generalize to object storage.
+//
+// There's no local release directory to stage into here, so none of the
+// release/rollback/manifest/delta machinery in releases.go and
+// manifest.go applies, and the Hugo build step buildHugoSite normally
+// runs is skipped. Configured webhooks still fire, so subscribers see
+// storage-backed deploys too.
+func handleDeployViaStorage(w http.ResponseWriter, r *http.Request, exportType, relativePath string) {
+	start := time.Now()
+
+	if r.FormValue("init") == "true" {
+		sendResponse(w, false, "ZIP site initialization is not supported with the configured storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	files := r.MultipartForm.File["files"]
+	if len(files) == 0 {
+		sendResponse(w, false, "No files sent", http.StatusBadRequest)
+		return
+	}
+
+	var processedFiles []FileInfo
+	var manifest []ManifestEntry
+	for _, fileHeader := range files {
+		filename := filepath.Base(fileHeader.Filename)
+		key := path.Join(exportType, relativePath, filename)
+
+		src, err := fileHeader.Open()
+		if err != nil {
+			logger.Printf("Error opening file %s: %v", filename, err)
+			continue
+		}
+
+		h := sha256.New()
+		err = activeStorage.Put(r.Context(), key, io.TeeReader(src, h), fileHeader.Size, fileHeader.Header.Get("Content-Type"))
+		src.Close()
+		if err != nil {
+			logger.Printf("Error uploading %s to storage: %v", key, err)
+			continue
+		}
+
+		processedFiles = append(processedFiles, FileInfo{
+			Path:        key,
+			ContentType: fileHeader.Header.Get("Content-Type"),
+			Size:        fileHeader.Size,
+		})
+		manifest = append(manifest, ManifestEntry{
+			Path:   key,
+			Size:   fileHeader.Size,
+			SHA256: hex.EncodeToString(h.Sum(nil)),
+		})
+		logger.Printf("File uploaded to storage: %s (%d bytes)", key, fileHeader.Size)
+	}
+
+	fireWebhooks(DeployWebhookPayload{
+		Event:      "deploy",
+		Release:    fmt.Sprintf("storage-%d", start.UnixNano()),
+		ExportType: exportType,
+		CommitID:   fmt.Sprintf("storage-%d", start.UnixNano()),
+		Files:      manifest,
+		DurationMS: time.Since(start).Milliseconds(),
+		Timestamp:  time.Now(),
+	})
+
+	writeJSON(w, http.StatusOK, struct {
+		Success bool       `json:"success"`
+		Message string     `json:"message"`
+		Files   []FileInfo `json:"files"`
+	}{
+		Success: true,
+		Message: fmt.Sprintf("Successfully uploaded %d files to storage", len(processedFiles)),
+		Files:   processedFiles,
+	})
+}
+
+// handlePresign returns a pre-signed PUT URL for the configured storage
+// backend, so a very large asset can be uploaded straight to the bucket
+// and only its metadata posted back to this API afterwards.
+func handlePresign(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		sendResponse(w, false, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.ParseForm()
+	key := r.FormValue("key")
+	if key == "" {
+		sendResponse(w, false, "Missing key", http.StatusBadRequest)
+		return
+	}
+
+	presigner, ok := activeStorage.(Presigner)
+	if !ok {
+		sendResponse(w, false, "The configured storage backend does not support presigned uploads", http.StatusNotImplemented)
+		return
+	}
+
+	expires := 15 * time.Minute
+	if raw := r.FormValue("expires_seconds"); raw != "" {
+		if secs, err := strconv.Atoi(raw); err == nil && secs > 0 {
+			expires = time.Duration(secs) * time.Second
+		}
+	}
+
+	url, err := presigner.PresignPut(r.Context(), key, expires)
+	if err != nil {
+		logger.Printf("Error generating presigned URL for %s: %v", key, err)
+		sendResponse(w, false, fmt.Sprintf("Error generating presigned URL: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Success bool   `json:"success"`
+		URL     string `json:"url"`
+		Key     string `json:"key"`
+	}{true, url, key})
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+)
+
+// azureStorage implements Storage against an Azure Blob Storage
+// container.
+type azureStorage struct {
+	client    *azblob.Client
+	container string
+	prefix    string
+}
+
+func newAzureStorage(cfg StorageConfig) (*azureStorage, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("azure storage requires a container name in bucket")
+	}
+	if cfg.ConnectionString == "" {
+		return nil, fmt.Errorf("azure storage requires a connection string")
+	}
+
+	client, err := azblob.NewClientFromConnectionString(cfg.ConnectionString, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating Azure Blob client: %w", err)
+	}
+
+	return &azureStorage{client: client, container: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+func (s *azureStorage) key(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return strings.TrimSuffix(s.prefix, "/") + "/" + strings.TrimPrefix(key, "/")
+}
+
+// blobClient returns a client for a single blob. The per-blob client
+// type lives in the azblob/blob subpackage, not azblob itself.
+func (s *azureStorage) blobClient(key string) *blob.Client {
+	return s.client.ServiceClient().NewContainerClient(s.container).NewBlobClient(s.key(key))
+}
+
+func (s *azureStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	opts := &azblob.UploadStreamOptions{}
+	if contentType != "" {
+		opts.HTTPHeaders = &blob.HTTPHeaders{BlobContentType: &contentType}
+	}
+	_, err := s.client.UploadStream(ctx, s.container, s.key(key), r, opts)
+	return err
+}
+
+func (s *azureStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := s.client.DownloadStream(ctx, s.container, s.key(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *azureStorage) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteBlob(ctx, s.container, s.key(key), nil)
+	return err
+}
+
+func (s *azureStorage) Stat(ctx context.Context, key string) (StorageObject, error) {
+	props, err := s.blobClient(key).GetProperties(ctx, nil)
+	if err != nil {
+		return StorageObject{}, err
+	}
+	obj := StorageObject{Key: key}
+	if props.ContentLength != nil {
+		obj.Size = *props.ContentLength
+	}
+	if props.ContentType != nil {
+		obj.ContentType = *props.ContentType
+	}
+	if props.LastModified != nil {
+		obj.ModTime = *props.LastModified
+	}
+	return obj, nil
+}
+
+func (s *azureStorage) List(ctx context.Context, prefix string) ([]StorageObject, error) {
+	var objects []StorageObject
+	containerClient := s.client.ServiceClient().NewContainerClient(s.container)
+	pager := containerClient.NewListBlobsFlatPager(&azblob.ListBlobsFlatOptions{
+		Prefix: strPtr(s.key(prefix)),
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Segment.BlobItems {
+			key := strings.TrimPrefix(strings.TrimPrefix(*item.Name, s.prefix), "/")
+			obj := StorageObject{Key: key}
+			if item.Properties.ContentLength != nil {
+				obj.Size = *item.Properties.ContentLength
+			}
+			if item.Properties.LastModified != nil {
+				obj.ModTime = *item.Properties.LastModified
+			}
+			objects = append(objects, obj)
+		}
+	}
+	return objects, nil
+}
+
+// PresignPut satisfies Presigner using a SAS-signed blob URL.
+func (s *azureStorage) PresignPut(ctx context.Context, key string, expires time.Duration) (string, error) {
+	permissions := sas.BlobPermissions{Write: true, Create: true}
+	return s.blobClient(key).GetSASURL(permissions, time.Now().Add(expires), nil)
+}
+
+func strPtr(s string) *string { return &s }
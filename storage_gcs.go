@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// gcsStorage implements Storage against a Google Cloud Storage bucket.
+type gcsStorage struct {
+	client *storage.Client
+	bucket string
+	prefix string
+}
+
+func newGCSStorage(cfg StorageConfig) (*gcsStorage, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("gcs storage requires a bucket")
+	}
+
+	var opts []option.ClientOption
+	if cfg.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(cfg.Endpoint))
+	}
+
+	client, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating GCS client: %w", err)
+	}
+
+	return &gcsStorage{client: client, bucket: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+func (s *gcsStorage) key(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return strings.TrimSuffix(s.prefix, "/") + "/" + strings.TrimPrefix(key, "/")
+}
+
+func (s *gcsStorage) object(key string) *storage.ObjectHandle {
+	return s.client.Bucket(s.bucket).Object(s.key(key))
+}
+
+func (s *gcsStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	w := s.object(key).NewWriter(ctx)
+	w.ContentType = contentType
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (s *gcsStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return s.object(key).NewReader(ctx)
+}
+
+func (s *gcsStorage) Delete(ctx context.Context, key string) error {
+	return s.object(key).Delete(ctx)
+}
+
+func (s *gcsStorage) Stat(ctx context.Context, key string) (StorageObject, error) {
+	attrs, err := s.object(key).Attrs(ctx)
+	if err != nil {
+		return StorageObject{}, err
+	}
+	return StorageObject{
+		Key:         key,
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+		ModTime:     attrs.Updated,
+	}, nil
+}
+
+func (s *gcsStorage) List(ctx context.Context, prefix string) ([]StorageObject, error) {
+	var objects []StorageObject
+	it := s.client.Bucket(s.bucket).Objects(ctx, &storage.Query{Prefix: s.key(prefix)})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		key := strings.TrimPrefix(strings.TrimPrefix(attrs.Name, s.prefix), "/")
+		objects = append(objects, StorageObject{
+			Key:     key,
+			Size:    attrs.Size,
+			ModTime: attrs.Updated,
+		})
+	}
+	return objects, nil
+}
+
+// PresignPut satisfies Presigner using a V4 signed URL.
+func (s *gcsStorage) PresignPut(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.client.Bucket(s.bucket).SignedURL(s.key(key), &storage.SignedURLOptions{
+		Method:  "PUT",
+		Expires: time.Now().Add(expires),
+	})
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tusResumableVersion is the tus protocol version implemented here.
+const tusResumableVersion = "1.0.0"
+
+// tusExtensions lists the tus extensions this server supports.
+const tusExtensions = "creation,creation-with-upload"
+
+// uploadsDir returns the scratch directory partial tus uploads are
+// stored under before they are moved into the normal deploy flow.
+func uploadsDir() string {
+	return filepath.Join(config.DeploymentPath, "uploads")
+}
+
+// tusUpload is the sidecar metadata tracked for each in-progress
+// resumable upload, persisted alongside its partial file as <id>.info.json.
+type tusUpload struct {
+	ID           string            `json:"id"`
+	Length       int64             `json:"length"`
+	Offset       int64             `json:"offset"`
+	Metadata     map[string]string `json:"metadata"`
+	ExportType   string            `json:"export_type"`
+	RelativePath string            `json:"relative_path"`
+	Filename     string            `json:"filename"`
+}
+
+// uploadPath returns the path of the partial upload's data file.
+func (u *tusUpload) uploadPath() string {
+	return filepath.Join(uploadsDir(), u.ID)
+}
+
+// infoPath returns the path of the partial upload's sidecar metadata file.
+func (u *tusUpload) infoPath() string {
+	return filepath.Join(uploadsDir(), u.ID+".info.json")
+}
+
+// save persists the upload's metadata to its sidecar file.
+func (u *tusUpload) save() error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(u.infoPath(), data, 0644)
+}
+
+// loadTusUpload reads a partial upload's sidecar metadata by ID.
+func loadTusUpload(id string) (*tusUpload, error) {
+	if strings.Contains(id, "/") || strings.Contains(id, "..") {
+		return nil, fmt.Errorf("invalid upload id")
+	}
+	data, err := os.ReadFile(filepath.Join(uploadsDir(), id+".info.json"))
+	if err != nil {
+		return nil, err
+	}
+	var u tusUpload
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// newUploadID generates a random, URL-safe identifier for a tus upload.
+func newUploadID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// parseUploadMetadata decodes a tus Upload-Metadata header, a
+// comma-separated list of "key base64(value)" pairs.
+func parseUploadMetadata(header string) map[string]string {
+	metadata := make(map[string]string)
+	if header == "" {
+		return metadata
+	}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, " ", 2)
+		key := parts[0]
+		value := ""
+		if len(parts) == 2 {
+			if decoded, err := base64.StdEncoding.DecodeString(parts[1]); err == nil {
+				value = string(decoded)
+			}
+		}
+		metadata[key] = value
+	}
+	return metadata
+}
+
+// setTusHeaders sets the headers common to every tus response.
+func setTusHeaders(w http.ResponseWriter) {
+	w.Header().Set("Tus-Resumable", tusResumableVersion)
+	w.Header().Set("Tus-Version", tusResumableVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+}
+
+// handleTusCreate implements the tus creation extension: POST /deploy/tus
+// opens a new resumable upload and returns its location.
+func handleTusCreate(w http.ResponseWriter, r *http.Request) {
+	setTusHeaders(w)
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		sendResponse(w, false, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isLocalStorageBackend() {
+		sendResponse(w, false, "Resumable uploads are not supported with the configured storage backend", http.StatusNotImplemented)
+		return
+	}
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		sendResponse(w, false, "Missing or invalid Upload-Length header", http.StatusBadRequest)
+		return
+	}
+
+	metadata := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+
+	exportType := metadata["export_type"]
+	if exportType == "" {
+		exportType = config.ExportType
+		if exportType == "" {
+			exportType = "hugo"
+		}
+	}
+
+	relativePath := metadata["relative_path"]
+	if strings.Contains(relativePath, "..") {
+		sendResponse(w, false, "Invalid relative path", http.StatusBadRequest)
+		return
+	}
+
+	filename := filepath.Base(metadata["filename"])
+	if filename == "" || filename == "." {
+		sendResponse(w, false, "Missing filename in Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+
+	if err := os.MkdirAll(uploadsDir(), 0755); err != nil {
+		logger.Printf("Error creating uploads directory: %v", err)
+		sendResponse(w, false, fmt.Sprintf("Error creating uploads directory: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		logger.Printf("Error generating upload id: %v", err)
+		sendResponse(w, false, "Error generating upload id", http.StatusInternalServerError)
+		return
+	}
+
+	upload := &tusUpload{
+		ID:           id,
+		Length:       length,
+		Offset:       0,
+		Metadata:     metadata,
+		ExportType:   exportType,
+		RelativePath: relativePath,
+		Filename:     filename,
+	}
+
+	file, err := os.Create(upload.uploadPath())
+	if err != nil {
+		logger.Printf("Error creating upload file: %v", err)
+		sendResponse(w, false, fmt.Sprintf("Error creating upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+	file.Close()
+
+	if err := upload.save(); err != nil {
+		logger.Printf("Error saving upload metadata: %v", err)
+		sendResponse(w, false, "Error saving upload metadata", http.StatusInternalServerError)
+		return
+	}
+
+	logger.Printf("Created tus upload %s for %s (%d bytes)", id, filename, length)
+
+	w.Header().Set("Location", "/deploy/tus/"+id)
+	w.Header().Set("Upload-Offset", "0")
+	w.WriteHeader(http.StatusCreated)
+
+	// creation-with-upload: a PATCH-equivalent body may be sent with the
+	// creation request itself.
+	if r.ContentLength > 0 && r.Header.Get("Content-Type") == "application/offset+octet-stream" {
+		appendToUpload(w, r, upload)
+	}
+}
+
+// handleTusUpload implements HEAD/PATCH on an existing upload at
+// /deploy/tus/<id>.
+func handleTusUpload(w http.ResponseWriter, r *http.Request) {
+	setTusHeaders(w)
+
+	id := strings.TrimPrefix(r.URL.Path, "/deploy/tus/")
+	if id == "" {
+		sendResponse(w, false, "Missing upload id", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodHead:
+		if !isLocalStorageBackend() {
+			sendResponse(w, false, "Resumable uploads are not supported with the configured storage backend", http.StatusNotImplemented)
+			return
+		}
+		upload, err := loadTusUpload(id)
+		if err != nil {
+			sendResponse(w, false, "Upload not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+	case http.MethodPatch:
+		if !isLocalStorageBackend() {
+			sendResponse(w, false, "Resumable uploads are not supported with the configured storage backend", http.StatusNotImplemented)
+			return
+		}
+		upload, err := loadTusUpload(id)
+		if err != nil {
+			sendResponse(w, false, "Upload not found", http.StatusNotFound)
+			return
+		}
+
+		if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+			sendResponse(w, false, "Invalid Content-Type", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+		if err != nil || offset != upload.Offset {
+			sendResponse(w, false, "Upload-Offset mismatch", http.StatusConflict)
+			return
+		}
+
+		appendToUpload(w, r, upload)
+	default:
+		sendResponse(w, false, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// appendToUpload appends the request body to a partial upload's data
+// file, updates its offset, and finalizes it into the normal deploy flow
+// once complete.
+func appendToUpload(w http.ResponseWriter, r *http.Request, upload *tusUpload) {
+	file, err := os.OpenFile(upload.uploadPath(), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		logger.Printf("Error opening upload %s: %v", upload.ID, err)
+		sendResponse(w, false, "Error opening upload", http.StatusInternalServerError)
+		return
+	}
+
+	written, copyErr := io.Copy(file, r.Body)
+	file.Close()
+
+	upload.Offset += written
+	if err := upload.save(); err != nil {
+		logger.Printf("Error saving upload progress for %s: %v", upload.ID, err)
+	}
+
+	if copyErr != nil {
+		logger.Printf("Error writing upload %s: %v", upload.ID, copyErr)
+		sendResponse(w, false, fmt.Sprintf("Error writing upload: %v", copyErr), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+
+	if upload.Offset < upload.Length {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if err := finishTusUpload(upload); err != nil {
+		logger.Printf("Error finishing upload %s: %v", upload.ID, err)
+		sendResponse(w, false, fmt.Sprintf("Error completing upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// finishTusUpload moves a completed tus upload into the normal deploy
+// flow: ZIP artifacts are extracted, everything else is placed at its
+// target relative path. Either way the result becomes a new release.
+func finishTusUpload(upload *tusUpload) error {
+	start := time.Now()
+	defer os.Remove(upload.uploadPath())
+	defer os.Remove(upload.infoPath())
+
+	deployPath := filepath.Join(config.DeploymentPath, upload.ExportType)
+	if upload.RelativePath != "" {
+		deployPath = filepath.Join(deployPath, upload.RelativePath)
+	}
+	if err := os.MkdirAll(deployPath, 0755); err != nil {
+		return fmt.Errorf("creating deployment directory: %w", err)
+	}
+
+	staging, err := newReleaseStaging(deployPath)
+	if err != nil {
+		return err
+	}
+
+	if strings.EqualFold(filepath.Ext(upload.Filename), ".zip") {
+		if err := extractZip(upload.uploadPath(), staging); err != nil {
+			os.RemoveAll(staging)
+			return fmt.Errorf("extracting uploaded zip: %w", err)
+		}
+	} else {
+		target := filepath.Join(staging, upload.Filename)
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			os.RemoveAll(staging)
+			return err
+		}
+		if err := copyFile(upload.uploadPath(), target); err != nil {
+			os.RemoveAll(staging)
+			return fmt.Errorf("placing uploaded file: %w", err)
+		}
+	}
+
+	// Build before finalizing: a failed Hugo build must never reach
+	// finalizeRelease, so current keeps pointing at the last good release.
+	if err := buildHugoSite(staging, upload.ExportType); err != nil {
+		os.RemoveAll(staging)
+		return fmt.Errorf("building site: %w", err)
+	}
+
+	releaseID, err := finalizeRelease(deployPath, staging)
+	if err != nil {
+		return err
+	}
+
+	notifyDeploy(deployPath, upload.ExportType, releaseID, start)
+
+	logger.Printf("Completed tus upload %s as release %s at %s", upload.ID, releaseID, deployPath)
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Defaults used when the corresponding Config field is unset.
+const (
+	defaultMaxUncompressedBytes = 1 << 30 // 1GB
+	defaultMaxEntries           = 10000
+	defaultMaxEntryBytes        = 200 << 20 // 200MB
+)
+
+func maxUncompressedBytes() int64 {
+	if config.MaxUncompressedBytes > 0 {
+		return config.MaxUncompressedBytes
+	}
+	return defaultMaxUncompressedBytes
+}
+
+func maxZipEntries() int {
+	if config.MaxEntries > 0 {
+		return config.MaxEntries
+	}
+	return defaultMaxEntries
+}
+
+func maxEntryBytes() int64 {
+	if config.MaxEntryBytes > 0 {
+		return config.MaxEntryBytes
+	}
+	return defaultMaxEntryBytes
+}
+
+// extractZip opens a ZIP file on disk and extracts it to destDir. It is
+// a thin wrapper around extractZipReader for callers that only have a
+// path, such as a completed tus upload.
+func extractZip(zipFile, destDir string) error {
+	f, err := os.Open(zipFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	return extractZipReader(f, info.Size(), destDir)
+}
+
+// extractZipReader extracts a ZIP archive read from r (an io.ReaderAt
+// over size bytes, e.g. the in-memory multipart file itself) to destDir
+// without ever buffering the whole upload to a temp file. It enforces
+// entry-count and size caps to guard against zip-bomb style abuse and
+// safely resolves or rejects symlink entries.
+func extractZipReader(r io.ReaderAt, size int64, destDir string) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	if len(zr.File) > maxZipEntries() {
+		return fmt.Errorf("zip has too many entries: %d (limit %d)", len(zr.File), maxZipEntries())
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+
+	cleanDest := filepath.Clean(destDir)
+	entryCap := maxEntryBytes()
+	var totalBytes int64
+	totalCap := maxUncompressedBytes()
+
+	for _, file := range zr.File {
+		name, err := sanitizeZipEntryName(file.Name)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(cleanDest, name)
+		if !strings.HasPrefix(path, cleanDest+string(os.PathSeparator)) && path != cleanDest {
+			return fmt.Errorf("illegal file path: %s", file.Name)
+		}
+
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(path, file.Mode()); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if file.Mode()&os.ModeSymlink != 0 {
+			if err := extractZipSymlink(file, path, cleanDest); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if int64(file.UncompressedSize64) > entryCap {
+			return fmt.Errorf("zip entry %s exceeds per-file size limit (%d > %d bytes)", file.Name, file.UncompressedSize64, entryCap)
+		}
+		totalBytes += int64(file.UncompressedSize64)
+		if totalBytes > totalCap {
+			return fmt.Errorf("zip exceeds total uncompressed size limit (%d bytes)", totalCap)
+		}
+
+		if err := extractZipFile(file, path, entryCap); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sanitizeZipEntryName rejects absolute paths, Windows drive prefixes,
+// and directory traversal in a ZIP entry name, returning the cleaned
+// relative path to extract it to.
+func sanitizeZipEntryName(name string) (string, error) {
+	slashName := filepath.ToSlash(name)
+	if strings.HasPrefix(slashName, "/") {
+		return "", fmt.Errorf("illegal absolute file path: %s", name)
+	}
+	if len(slashName) >= 2 && slashName[1] == ':' {
+		return "", fmt.Errorf("illegal drive-prefixed file path: %s", name)
+	}
+
+	clean := filepath.FromSlash(filepath.Clean(slashName))
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("illegal file path: %s", name)
+	}
+	return clean, nil
+}
+
+// extractZipFile writes a single ZIP entry's contents to path,
+// preserving its modtime, and aborts if the decompressed stream exceeds
+// cap bytes regardless of what the entry's header claims.
+func extractZipFile(file *zip.File, path string, cap int64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
+	if err != nil {
+		return err
+	}
+
+	written, err := io.Copy(dst, io.LimitReader(src, cap+1))
+	if err != nil {
+		dst.Close()
+		return err
+	}
+	if written > cap {
+		dst.Close()
+		os.Remove(path)
+		return fmt.Errorf("zip entry %s exceeds per-file size limit (%d bytes)", file.Name, cap)
+	}
+
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(path, file.Modified, file.Modified)
+}
+
+// extractZipSymlink recreates a symlink entry, rejecting it if its
+// target would resolve outside destDir.
+func extractZipSymlink(file *zip.File, path, destDir string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	targetBytes, err := io.ReadAll(io.LimitReader(src, 4096))
+	if err != nil {
+		return err
+	}
+	target := string(targetBytes)
+
+	resolved := target
+	if !filepath.IsAbs(resolved) {
+		resolved = filepath.Join(filepath.Dir(path), target)
+	}
+	resolved = filepath.Clean(resolved)
+	if !strings.HasPrefix(resolved, destDir+string(os.PathSeparator)) && resolved != destDir {
+		return fmt.Errorf("symlink entry %s escapes destination directory: %s", file.Name, target)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	os.Remove(path)
+	return os.Symlink(target, path)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildZip writes a crafted ZIP archive to a temporary buffer using
+// write, which receives the *zip.Writer to add whatever entries the
+// test needs, including hostile ones such as symlinks and traversal
+// names.
+func buildZip(t *testing.T, write func(zw *zip.Writer)) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write(zw)
+	if err := zw.Close(); err != nil {
+		t.Fatalf("closing zip writer: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func addZipFile(zw *zip.Writer, name string, mode os.FileMode, content []byte) {
+	hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	hdr.SetMode(mode)
+	w, _ := zw.CreateHeader(hdr)
+	w.Write(content)
+}
+
+func TestExtractZipRejectsDirectoryTraversal(t *testing.T) {
+	dest := t.TempDir()
+	r := buildZip(t, func(zw *zip.Writer) {
+		addZipFile(zw, "../../etc/passwd", 0644, []byte("pwned"))
+	})
+
+	if err := extractZipReader(r, r.Size(), dest); err == nil {
+		t.Fatal("expected an error for a traversal entry, got nil")
+	}
+}
+
+func TestExtractZipRejectsAbsolutePath(t *testing.T) {
+	dest := t.TempDir()
+	r := buildZip(t, func(zw *zip.Writer) {
+		addZipFile(zw, "/etc/passwd", 0644, []byte("pwned"))
+	})
+
+	if err := extractZipReader(r, r.Size(), dest); err == nil {
+		t.Fatal("expected an error for an absolute path entry, got nil")
+	}
+}
+
+func TestExtractZipRejectsWindowsDrivePrefix(t *testing.T) {
+	dest := t.TempDir()
+	r := buildZip(t, func(zw *zip.Writer) {
+		addZipFile(zw, `C:\Windows\System32\evil.dll`, 0644, []byte("pwned"))
+	})
+
+	if err := extractZipReader(r, r.Size(), dest); err == nil {
+		t.Fatal("expected an error for a drive-prefixed path entry, got nil")
+	}
+}
+
+func TestExtractZipRejectsTooManyEntries(t *testing.T) {
+	config.MaxEntries = 3
+	defer func() { config.MaxEntries = 0 }()
+
+	dest := t.TempDir()
+	r := buildZip(t, func(zw *zip.Writer) {
+		for i := 0; i < 5; i++ {
+			addZipFile(zw, "file.txt", 0644, []byte("x"))
+		}
+	})
+
+	if err := extractZipReader(r, r.Size(), dest); err == nil {
+		t.Fatal("expected an error for too many entries, got nil")
+	}
+}
+
+func TestExtractZipRejectsOversizedEntry(t *testing.T) {
+	config.MaxEntryBytes = 4
+	defer func() { config.MaxEntryBytes = 0 }()
+
+	dest := t.TempDir()
+	r := buildZip(t, func(zw *zip.Writer) {
+		addZipFile(zw, "big.bin", 0644, []byte("way too big for the cap"))
+	})
+
+	if err := extractZipReader(r, r.Size(), dest); err == nil {
+		t.Fatal("expected an error for an oversized entry, got nil")
+	}
+}
+
+func TestExtractZipRejectsEscapingSymlink(t *testing.T) {
+	dest := t.TempDir()
+	r := buildZip(t, func(zw *zip.Writer) {
+		addZipFile(zw, "evil-link", os.ModeSymlink|0777, []byte("../../../etc"))
+	})
+
+	if err := extractZipReader(r, r.Size(), dest); err == nil {
+		t.Fatal("expected an error for a symlink escaping destDir, got nil")
+	}
+}
+
+func TestExtractZipAllowsSafeSymlink(t *testing.T) {
+	dest := t.TempDir()
+	r := buildZip(t, func(zw *zip.Writer) {
+		addZipFile(zw, "real.txt", 0644, []byte("hello"))
+		addZipFile(zw, "link.txt", os.ModeSymlink|0777, []byte("real.txt"))
+	})
+
+	if err := extractZipReader(r, r.Size(), dest); err != nil {
+		t.Fatalf("extracting a safe symlink should succeed, got: %v", err)
+	}
+
+	target, err := os.Readlink(filepath.Join(dest, "link.txt"))
+	if err != nil {
+		t.Fatalf("expected link.txt to be a symlink: %v", err)
+	}
+	if target != "real.txt" {
+		t.Fatalf("expected symlink target real.txt, got %q", target)
+	}
+}
+
+func TestExtractZipValidEntries(t *testing.T) {
+	dest := t.TempDir()
+	r := buildZip(t, func(zw *zip.Writer) {
+		addZipFile(zw, "index.html", 0644, []byte("<html></html>"))
+		addZipFile(zw, "css/style.css", 0644, []byte("body{}"))
+	})
+
+	if err := extractZipReader(r, r.Size(), dest); err != nil {
+		t.Fatalf("extracting valid entries should succeed, got: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dest, "index.html"))
+	if err != nil {
+		t.Fatalf("reading extracted file: %v", err)
+	}
+	if string(data) != "<html></html>" {
+		t.Fatalf("unexpected content: %q", data)
+	}
+}